@@ -0,0 +1,76 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/phylake/go-crypto"
+)
+
+// There is deliberately no test asserting aes.NewCipher returns a ctrAble
+// (a type with NewCTR([]byte) cipher.Stream) as a proxy for "the AES-NI/
+// ARMv8 fast path is active": on this stdlib version crypto/aes's Block
+// never implements that interface on amd64/arm64, only on s390x (see
+// crypto/aes/ctr_s390x.go upstream). Here the hardware-accelerated path is
+// crypto/cipher.NewCTR's generic buffered-keystream loop calling into
+// Block.Encrypt/Decrypt, which the assembly-accelerated aesCipherAsm
+// implements directly — there's no exported or structurally-typeable hook
+// to assert that from this package. The benchmarks below are the
+// regression signal instead: a wrapper that forces the slow generic path
+// shows up as a throughput cliff between runs, not as a failed assertion.
+func benchmarkCTRReader(b *testing.B, size int) {
+	key, err := crypto.RandomAES256Key()
+	if err != nil {
+		b.Fatal(err)
+	}
+	plaintext := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r, err := NewCTRReader(key, bytes.NewReader(plaintext))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCTRReader16B(b *testing.B)  { benchmarkCTRReader(b, 16) }
+func BenchmarkCTRReader1KiB(b *testing.B) { benchmarkCTRReader(b, 1024) }
+func BenchmarkCTRReader8KiB(b *testing.B) { benchmarkCTRReader(b, 8*1024) }
+func BenchmarkCTRReader1MiB(b *testing.B) { benchmarkCTRReader(b, 1024*1024) }
+
+func benchmarkCTRWriter(b *testing.B, size int) {
+	key, err := crypto.RandomAES256Key()
+	if err != nil {
+		b.Fatal(err)
+	}
+	ciphertext := make([]byte, aes.BlockSize+size)
+	if _, err := io.ReadFull(rand.Reader, ciphertext); err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := NewCTRWriter(key, ioutil.Discard)
+		if _, err := w.Write(ciphertext); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCTRWriter16B(b *testing.B)  { benchmarkCTRWriter(b, 16) }
+func BenchmarkCTRWriter1KiB(b *testing.B) { benchmarkCTRWriter(b, 1024) }
+func BenchmarkCTRWriter8KiB(b *testing.B) { benchmarkCTRWriter(b, 8*1024) }
+func BenchmarkCTRWriter1MiB(b *testing.B) { benchmarkCTRWriter(b, 1024*1024) }
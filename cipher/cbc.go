@@ -0,0 +1,201 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// ErrInvalidPadding is returned by a CBC reader when the final block's
+// PKCS#7 padding is malformed, which usually means the key is wrong or the
+// ciphertext was truncated or corrupted.
+var ErrInvalidPadding = errors.New("cipher: invalid PKCS#7 padding")
+
+// cbcWriter buffers plaintext until it has a full AES block, encrypts that
+// block under CBC, and writes the ciphertext to the wrapped io.Writer. The
+// final, possibly partial, block is PKCS#7 padded and flushed on Close.
+type cbcWriter struct {
+	mode cipher.BlockMode
+	w    io.Writer
+
+	iv      []byte
+	wroteIV bool
+	buf     []byte
+}
+
+// NewCBCWriter returns an io.WriteCloser that AES-CBC encrypts everything
+// written to it, PKCS#7 padding and flushing the final block on Close. It
+// writes a random IV ahead of the first ciphertext block. Close must be
+// called even if nothing was ever written, so the padded empty block gets
+// emitted.
+func NewCBCWriter(key []byte, w io.Writer) (io.WriteCloser, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+
+	return &cbcWriter{mode: cipher.NewCBCEncrypter(block, iv), iv: iv, w: w}, nil
+}
+
+func (w *cbcWriter) Write(p []byte) (int, error) {
+	if err := w.flushIV(); err != nil {
+		return 0, err
+	}
+
+	written := len(p)
+	w.buf = append(w.buf, p...)
+
+	n := len(w.buf) - len(w.buf)%aes.BlockSize
+	if n > 0 {
+		ciphertext := make([]byte, n)
+		w.mode.CryptBlocks(ciphertext, w.buf[:n])
+		if _, err := w.w.Write(ciphertext); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[n:]
+	}
+
+	return written, nil
+}
+
+// Close pads whatever plaintext remains buffered to a full block with
+// PKCS#7 and writes it. It does not close the underlying writer.
+func (w *cbcWriter) Close() error {
+	if err := w.flushIV(); err != nil {
+		return err
+	}
+
+	padded := pkcs7Pad(w.buf, aes.BlockSize)
+	ciphertext := make([]byte, len(padded))
+	w.mode.CryptBlocks(ciphertext, padded)
+	w.buf = nil
+
+	_, err := w.w.Write(ciphertext)
+	return err
+}
+
+func (w *cbcWriter) flushIV() error {
+	if w.wroteIV {
+		return nil
+	}
+	_, err := w.w.Write(w.iv)
+	w.wroteIV = true
+	return err
+}
+
+// cbcReader reads an IV followed by AES-CBC ciphertext blocks from the
+// wrapped io.Reader, decrypting each. It holds back one decrypted block so
+// that once the source is exhausted it can strip the final block's PKCS#7
+// padding before returning it.
+type cbcReader struct {
+	block  cipher.Block
+	mode   cipher.BlockMode
+	ivRead bool
+	r      io.Reader
+
+	pending []byte
+	out     []byte
+	done    bool
+}
+
+// NewCBCReader returns an io.Reader that reads an IV followed by AES-CBC
+// ciphertext (as written by a cbcWriter) from r and yields the decrypted,
+// unpadded plaintext.
+func NewCBCReader(key []byte, r io.Reader) (io.Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &cbcReader{block: block, r: r}, nil
+}
+
+func (r *cbcReader) Read(p []byte) (int, error) {
+	if !r.ivRead {
+		iv := make([]byte, aes.BlockSize)
+		if _, err := io.ReadFull(r.r, iv); err != nil {
+			return 0, err
+		}
+		r.mode = cipher.NewCBCDecrypter(r.block, iv)
+		r.ivRead = true
+	}
+
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *cbcReader) fill() error {
+	block := make([]byte, aes.BlockSize)
+	_, err := io.ReadFull(r.r, block)
+	if err == io.EOF {
+		unpadded, uerr := pkcs7Unpad(r.pending)
+		if uerr != nil {
+			return uerr
+		}
+		r.out, r.pending, r.done = unpadded, nil, true
+		return nil
+	}
+	if err == io.ErrUnexpectedEOF {
+		// a partial trailing block means truncated/corrupt ciphertext,
+		// not a valid end of stream; don't let it fall into the padding
+		// check, which would silently accept it some of the time.
+		return ErrInvalidPadding
+	}
+	if err != nil {
+		return err
+	}
+
+	plain := make([]byte, aes.BlockSize)
+	r.mode.CryptBlocks(plain, block)
+
+	if r.pending != nil {
+		r.out = append(r.out, r.pending...)
+	}
+	r.pending = plain
+	return nil
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	padLen := blockSize - len(data)%blockSize
+	padded := make([]byte, len(data)+padLen)
+	copy(padded, data)
+	for i := len(data); i < len(padded); i++ {
+		padded[i] = byte(padLen)
+	}
+	return padded
+}
+
+func pkcs7Unpad(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrInvalidPadding
+	}
+
+	padLen := int(data[len(data)-1])
+	if padLen == 0 || padLen > len(data) {
+		return nil, ErrInvalidPadding
+	}
+
+	for _, b := range data[len(data)-padLen:] {
+		if int(b) != padLen {
+			return nil, ErrInvalidPadding
+		}
+	}
+
+	return data[:len(data)-padLen], nil
+}
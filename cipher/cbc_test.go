@@ -0,0 +1,67 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/phylake/go-crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CBC_Bijection(t *testing.T) {
+	t.Parallel()
+
+	// exercise a payload shorter than a block, exactly one block, and
+	// several blocks plus a short remainder.
+	for _, size := range []int{0, 5, 16, 16 * 3, 16*3 + 7} {
+		size := size
+		t.Run("", func(t *testing.T) {
+			t.Parallel()
+
+			plaintext := make([]byte, size)
+			_, err := io.ReadFull(rand.Reader, plaintext)
+			assert.Nil(t, err)
+
+			key, err := crypto.RandomAES256Key()
+			assert.Nil(t, err)
+
+			var ciphertext bytes.Buffer
+			w, err := NewCBCWriter(key, &ciphertext)
+			assert.Nil(t, err)
+			_, err = w.Write(plaintext)
+			assert.Nil(t, err)
+			assert.Nil(t, w.Close())
+
+			r, err := NewCBCReader(key, &ciphertext)
+			assert.Nil(t, err)
+			roundTripped, err := io.ReadAll(r)
+			assert.Nil(t, err)
+
+			assert.Equal(t, plaintext, roundTripped)
+		})
+	}
+}
+
+func Test_CBC_RejectsBadPadding(t *testing.T) {
+	t.Parallel()
+
+	key, err := crypto.RandomAES256Key()
+	assert.Nil(t, err)
+
+	var ciphertext bytes.Buffer
+	w, err := NewCBCWriter(key, &ciphertext)
+	assert.Nil(t, err)
+	_, err = w.Write([]byte("some plaintext"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	tampered := ciphertext.Bytes()
+	tampered[len(tampered)-1] ^= 0xFF
+
+	r, err := NewCBCReader(key, bytes.NewReader(tampered))
+	assert.Nil(t, err)
+	_, err = io.ReadAll(r)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,67 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"io"
+)
+
+// ctrReaderAt decrypts arbitrary byte ranges of an `iv || ciphertext` blob
+// (as produced by NewCTRReader) without reading from byte
+// zero, since CTR's keystream at any offset depends only on the IV and
+// that offset's block index.
+type ctrReaderAt struct {
+	key []byte
+	iv  []byte
+	r   io.ReaderAt
+}
+
+// NewCTRReaderAt returns an io.ReaderAt that decrypts arbitrary ranges of
+// the plaintext from an `iv || ciphertext` blob read through r, without
+// needing to stream from the start. This is useful for random-access
+// decryption of large, disk- or S3-backed ciphertext.
+func NewCTRReaderAt(key []byte, r io.ReaderAt) (io.ReaderAt, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := r.ReadAt(iv, 0); err != nil {
+		return nil, err
+	}
+	return &ctrReaderAt{key: key, iv: iv, r: r}, nil
+}
+
+func (r *ctrReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("cipher: negative ReadAt offset")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	blockIndex := off / aes.BlockSize
+	discard := int(off % aes.BlockSize)
+
+	ciphertext := make([]byte, discard+len(p))
+	n, err := r.r.ReadAt(ciphertext, int64(len(r.iv))+blockIndex*aes.BlockSize)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	ciphertext = ciphertext[:n]
+	if len(ciphertext) <= discard {
+		return 0, io.EOF
+	}
+
+	block, cerr := aes.NewCipher(r.key)
+	if cerr != nil {
+		return 0, cerr
+	}
+	stream := cipher.NewCTR(block, addCounter(r.iv, blockIndex))
+
+	plain := make([]byte, len(ciphertext))
+	stream.XORKeyStream(plain, ciphertext)
+
+	copied := copy(p, plain[discard:])
+	if copied < len(p) {
+		return copied, io.EOF
+	}
+	return copied, nil
+}
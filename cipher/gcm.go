@@ -0,0 +1,191 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// DefaultGCMChunkSize is the plaintext size of each authenticated frame
+// produced by gcmWriter / consumed by gcmReader when no explicit chunk
+// size is given.
+const DefaultGCMChunkSize = 64 * 1024
+
+// ErrGCMChunkTooLarge is returned when a frame's declared length exceeds
+// what the reader is willing to buffer.
+var ErrGCMChunkTooLarge = errors.New("cipher: GCM chunk length exceeds maximum")
+
+// gcmWriter buffers plaintext until it has a full chunk (or Close is
+// called), then seals it with AES-GCM and emits a
+// `nonce || chunk-length || ciphertext+tag` frame to the wrapped writer.
+type gcmWriter struct {
+	aead      cipher.AEAD
+	w         io.Writer
+	chunkSize int
+	buf       []byte
+}
+
+// NewGCMWriter returns an io.WriteCloser that encrypts everything written
+// to it in chunkSize plaintext chunks, sealing each with AES-GCM and
+// writing a `nonce || chunk-length || ciphertext+tag` frame per chunk. The
+// nonce is freshly random for every frame. Close must be called to flush
+// any buffered plaintext shorter than a full chunk.
+func NewGCMWriter(key []byte, w io.Writer, chunkSize int) (io.WriteCloser, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultGCMChunkSize
+	}
+	return &gcmWriter{aead: aead, w: w, chunkSize: chunkSize}, nil
+}
+
+func (w *gcmWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := w.chunkSize - len(w.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+
+		if len(w.buf) == w.chunkSize {
+			if err := w.flushChunk(w.buf); err != nil {
+				// the bytes just appended were never durably written, so
+				// drop them again: a caller reacting to a short write by
+				// retrying with p[written:] must not find them already
+				// buffered, or it'll end up duplicating them.
+				w.buf = w.buf[:len(w.buf)-n]
+				return written, err
+			}
+			w.buf = w.buf[:0]
+		}
+
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals and writes any buffered plaintext shorter than chunkSize.
+// It does not close the underlying writer.
+func (w *gcmWriter) Close() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	defer func() { w.buf = nil }()
+	return w.flushChunk(w.buf)
+}
+
+func (w *gcmWriter) flushChunk(chunk []byte) error {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	sealed := w.aead.Seal(nil, nonce, chunk, nil)
+
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(sealed)))
+
+	if _, err := w.w.Write(nonce); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(length); err != nil {
+		return err
+	}
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+// gcmReader reads `nonce || chunk-length || ciphertext+tag` frames from the
+// wrapped reader, verifying and decrypting each before yielding its
+// plaintext.
+type gcmReader struct {
+	aead      cipher.AEAD
+	r         io.Reader
+	chunkSize int
+	plain     []byte
+	err       error
+}
+
+// NewGCMReader returns an io.Reader that reads frames written by a
+// gcmWriter, rejecting the stream with an error as soon as any frame fails
+// authentication. chunkSize bounds the largest ciphertext+tag frame the
+// reader will buffer; it should match the chunkSize used to write the
+// stream (DefaultGCMChunkSize if the writer used the default).
+func NewGCMReader(key []byte, r io.Reader, chunkSize int) (io.Reader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultGCMChunkSize
+	}
+	return &gcmReader{aead: aead, r: r, chunkSize: chunkSize}, nil
+}
+
+func (r *gcmReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if err := r.readChunk(); err != nil {
+			r.err = err
+			if err == io.EOF && len(r.plain) == 0 {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *gcmReader) readChunk() error {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := io.ReadFull(r.r, nonce); err != nil {
+		return err
+	}
+
+	lengthBuf := make([]byte, 4)
+	if _, err := io.ReadFull(r.r, lengthBuf); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	length := binary.BigEndian.Uint32(lengthBuf)
+	if length > uint32(r.chunkSize+r.aead.Overhead()) {
+		return ErrGCMChunkTooLarge
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(r.r, sealed); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	plain, err := r.aead.Open(sealed[:0], nonce, sealed, nil)
+	if err != nil {
+		return err
+	}
+	r.plain = plain
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
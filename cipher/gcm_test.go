@@ -0,0 +1,60 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/phylake/go-crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GCM_Bijection(t *testing.T) {
+	t.Parallel()
+
+	plaintext := make([]byte, 200*1024+17) // spans multiple chunks plus a short final one
+	_, err := io.ReadFull(rand.Reader, plaintext)
+	assert.Nil(t, err)
+
+	key, err := crypto.RandomAES256Key()
+	assert.Nil(t, err)
+
+	var ciphertext bytes.Buffer
+	w, err := NewGCMWriter(key, &ciphertext, 64*1024)
+	assert.Nil(t, err)
+	_, err = w.Write(plaintext)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	r, err := NewGCMReader(key, &ciphertext, 64*1024)
+	assert.Nil(t, err)
+	roundTripped, err := io.ReadAll(r)
+	assert.Nil(t, err)
+
+	assert.Equal(t, plaintext, roundTripped)
+}
+
+func Test_GCM_DetectsTampering(t *testing.T) {
+	t.Parallel()
+
+	plaintext := []byte("some plaintext that fits in one chunk")
+
+	key, err := crypto.RandomAES256Key()
+	assert.Nil(t, err)
+
+	var ciphertext bytes.Buffer
+	w, err := NewGCMWriter(key, &ciphertext, DefaultGCMChunkSize)
+	assert.Nil(t, err)
+	_, err = w.Write(plaintext)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	tampered := ciphertext.Bytes()
+	tampered[len(tampered)-1] ^= 0xFF
+
+	r, err := NewGCMReader(key, bytes.NewReader(tampered), DefaultGCMChunkSize)
+	assert.Nil(t, err)
+	_, err = io.ReadAll(r)
+	assert.NotNil(t, err)
+}
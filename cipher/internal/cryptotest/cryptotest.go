@@ -0,0 +1,174 @@
+// Package cryptotest holds a reusable conformance suite for
+// crypto/cipher.Stream implementations, mirroring the invariants the
+// stdlib's own stream modes (CTR, CFB, OFB) are expected to uphold. New
+// modes added to this module can be dropped into TestStream to get the
+// same coverage for free.
+package cryptotest
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+)
+
+// TestStream exercises newStream (which must return a fresh, independent
+// cipher.Stream seeded with the same key/IV on every call) against the
+// invariants callers of cipher.Stream rely on. It assumes the mode is its
+// own inverse (CTR, OFB): newStream is used for both the encrypt and
+// decrypt direction. Modes where that doesn't hold (CFB) should use
+// TestStreamPair instead.
+func TestStream(t *testing.T, newStream func() cipher.Stream) {
+	TestStreamPair(t, newStream, newStream)
+}
+
+// TestStreamPair is like TestStream but takes separate constructors for
+// the encrypt and decrypt directions, for modes like CFB whose keystream
+// application differs depending on which direction it's used for.
+func TestStreamPair(t *testing.T, newEncryptStream, newDecryptStream func() cipher.Stream) {
+	t.Run("XORTwiceReturnsOriginal", func(t *testing.T) { testXORTwice(t, newEncryptStream, newDecryptStream) })
+	t.Run("FragmentsMatchBulk", func(t *testing.T) { testFragmentsMatchBulk(t, newEncryptStream) })
+	t.Run("ShortDstPanics", func(t *testing.T) { testShortDstPanics(t, newEncryptStream) })
+	t.Run("Overlap", func(t *testing.T) { testOverlap(t, newEncryptStream) })
+	t.Run("RoundTripAcrossBlockBoundaries", func(t *testing.T) { testRoundTrip(t, newEncryptStream, newDecryptStream) })
+	t.Run("ReaderFragmentationMatchesBulk", func(t *testing.T) { testReaderFragmentation(t, newEncryptStream) })
+}
+
+func testXORTwice(t *testing.T, newEncryptStream, newDecryptStream func() cipher.Stream) {
+	original := make([]byte, 256)
+	for i := range original {
+		original[i] = byte(i)
+	}
+
+	ciphertext := make([]byte, len(original))
+	newEncryptStream().XORKeyStream(ciphertext, original)
+
+	plaintext := make([]byte, len(original))
+	newDecryptStream().XORKeyStream(plaintext, ciphertext)
+
+	if !bytes.Equal(plaintext, original) {
+		t.Fatalf("encrypting then decrypting with fresh streams did not return the original")
+	}
+}
+
+func testFragmentsMatchBulk(t *testing.T, newStream func() cipher.Stream) {
+	src := make([]byte, 256)
+	for i := range src {
+		src[i] = byte(i)
+	}
+
+	bulk := make([]byte, len(src))
+	newStream().XORKeyStream(bulk, src)
+
+	for _, fragSize := range []int{1, 2, 4, 8, 16, 32, 64, 128, 256} {
+		fragSize := fragSize
+		fragmented := make([]byte, len(src))
+		stream := newStream()
+		for off := 0; off < len(src); off += fragSize {
+			end := off + fragSize
+			if end > len(src) {
+				end = len(src)
+			}
+			stream.XORKeyStream(fragmented[off:end], src[off:end])
+		}
+
+		if !bytes.Equal(bulk, fragmented) {
+			t.Errorf("fragment size %d: streaming in fragments diverged from a single bulk call", fragSize)
+		}
+	}
+}
+
+func testShortDstPanics(t *testing.T, newStream func() cipher.Stream) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("XORKeyStream with len(dst) < len(src) did not panic")
+		}
+	}()
+
+	stream := newStream()
+	src := make([]byte, 32)
+	dst := make([]byte, 16)
+	stream.XORKeyStream(dst, src)
+}
+
+func testOverlap(t *testing.T, newStream func() cipher.Stream) {
+	t.Run("FullyOverlapping", func(t *testing.T) {
+		buf := make([]byte, 64)
+		for i := range buf {
+			buf[i] = byte(i)
+		}
+		newStream().XORKeyStream(buf, buf)
+	})
+
+	t.Run("PartiallyOverlapping", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("XORKeyStream with partially overlapping dst/src did not panic")
+			}
+		}()
+
+		buf := make([]byte, 64)
+		stream := newStream()
+		stream.XORKeyStream(buf[1:], buf[:63])
+	})
+}
+
+func testRoundTrip(t *testing.T, newEncryptStream, newDecryptStream func() cipher.Stream) {
+	// sizes chosen to straddle AES's 16 byte block boundary in both
+	// directions.
+	for _, size := range []int{1, 15, 16, 17, 31, 32, 33, 256} {
+		size := size
+		plaintext := make([]byte, size)
+		for i := range plaintext {
+			plaintext[i] = byte(i)
+		}
+
+		for _, fragSize := range []int{1, 3, 16, size} {
+			ciphertext := make([]byte, size)
+			encStream := newEncryptStream()
+			for off := 0; off < size; off += fragSize {
+				end := off + fragSize
+				if end > size {
+					end = size
+				}
+				encStream.XORKeyStream(ciphertext[off:end], plaintext[off:end])
+			}
+
+			decrypted := make([]byte, size)
+			decStream := newDecryptStream()
+			decStream.XORKeyStream(decrypted, ciphertext)
+
+			if !bytes.Equal(plaintext, decrypted) {
+				t.Errorf("size %d, fragment %d: encrypt/decrypt round trip did not recover the plaintext", size, fragSize)
+			}
+		}
+	}
+}
+
+func testReaderFragmentation(t *testing.T, newStream func() cipher.Stream) {
+	src := make([]byte, 512)
+	for i := range src {
+		src[i] = byte(i)
+	}
+
+	bulk := make([]byte, len(src))
+	newStream().XORKeyStream(bulk, src)
+
+	readers := map[string]func(io.Reader) io.Reader{
+		"OneByteReader": iotest.OneByteReader,
+		"HalfReader":    iotest.HalfReader,
+	}
+
+	for name, wrap := range readers {
+		sr := cipher.StreamReader{S: newStream(), R: wrap(bytes.NewReader(src))}
+		got, err := ioutil.ReadAll(sr)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(bulk, got) {
+			t.Errorf("%s: reading through a fragmenting io.Reader diverged from a single bulk call", name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+)
+
+// Mode adapts a block cipher mode of operation (CTR, CFB, OFB, ...) to the
+// streamReader/streamWriter plumbing below. Encrypter and Decrypter are
+// split because some modes, CFB in particular, apply the keystream
+// differently depending on direction; symmetric modes just return the same
+// cipher.Stream from both.
+type Mode interface {
+	Encrypter(block cipher.Block, iv []byte) cipher.Stream
+	Decrypter(block cipher.Block, iv []byte) cipher.Stream
+}
+
+// symmetricMode adapts a mode constructor that's its own inverse (CTR, OFB)
+// to the Mode interface.
+type symmetricMode func(block cipher.Block, iv []byte) cipher.Stream
+
+func (m symmetricMode) Encrypter(block cipher.Block, iv []byte) cipher.Stream { return m(block, iv) }
+func (m symmetricMode) Decrypter(block cipher.Block, iv []byte) cipher.Stream { return m(block, iv) }
+
+type cfbMode struct{}
+
+func (cfbMode) Encrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return cipher.NewCFBEncrypter(block, iv)
+}
+
+func (cfbMode) Decrypter(block cipher.Block, iv []byte) cipher.Stream {
+	return cipher.NewCFBDecrypter(block, iv)
+}
+
+var (
+	// CTR is counter mode: a symmetric stream cipher that also supports
+	// random access (see NewCTRReaderAt).
+	CTR Mode = symmetricMode(cipher.NewCTR)
+
+	// OFB is output feedback mode: a symmetric stream cipher.
+	OFB Mode = symmetricMode(cipher.NewOFB)
+
+	// CFB is cipher feedback mode: self-synchronizing, with distinct
+	// encrypt/decrypt keystream application.
+	CFB Mode = cfbMode{}
+)
+
+// streamReader encrypts everything read from the wrapped io.Reader under
+// mode and prefixes the stream with the IV so the receiving end can
+// recover it.
+type streamReader struct {
+	iv    []byte
+	ivPos int
+	sr    *cipher.StreamReader
+}
+
+// NewStreamReader returns an io.Reader that reads plaintext from r and
+// yields `iv || ciphertext`, encrypted under key using mode. A random IV is
+// generated for each reader.
+func NewStreamReader(key []byte, r io.Reader, mode Mode) (io.Reader, error) {
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	return newStreamReaderWithVector(key, r, iv, mode)
+}
+
+func newStreamReaderWithVector(key []byte, r io.Reader, iv []byte, mode Mode) (io.Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &streamReader{
+		iv: iv,
+		sr: &cipher.StreamReader{S: mode.Encrypter(block, iv), R: r},
+	}, nil
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	n := 0
+	if r.ivPos < len(r.iv) {
+		n = copy(p, r.iv[r.ivPos:])
+		r.ivPos += n
+		if n == len(p) {
+			return n, nil
+		}
+	}
+
+	m, err := r.sr.Read(p[n:])
+	return n + m, err
+}
+
+// streamWriter decrypts everything written to it, assuming the first
+// aes.BlockSize bytes written are the IV, and writes the resulting
+// plaintext to the wrapped io.Writer.
+type streamWriter struct {
+	key  []byte
+	w    io.Writer
+	mode Mode
+
+	iv []byte
+	sw *cipher.StreamWriter
+}
+
+// NewStreamWriter returns an io.Writer that expects `iv || ciphertext`
+// written to it, decrypts under key using mode, and writes the resulting
+// plaintext to w.
+func NewStreamWriter(key []byte, w io.Writer, mode Mode) io.Writer {
+	return &streamWriter{key: key, w: w, mode: mode, iv: make([]byte, 0, aes.BlockSize)}
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	total := 0
+
+	if w.sw == nil {
+		need := aes.BlockSize - len(w.iv)
+		if need > len(p) {
+			need = len(p)
+		}
+		w.iv = append(w.iv, p[:need]...)
+		p = p[need:]
+		total += need
+
+		if len(w.iv) < aes.BlockSize {
+			return total, nil
+		}
+
+		block, err := aes.NewCipher(w.key)
+		if err != nil {
+			return total, err
+		}
+		w.sw = &cipher.StreamWriter{S: w.mode.Decrypter(block, w.iv), W: w.w}
+	}
+
+	if len(p) == 0 {
+		return total, nil
+	}
+
+	n, err := w.sw.Write(p)
+	return total + n, err
+}
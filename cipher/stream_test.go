@@ -0,0 +1,70 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/phylake/go-crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StreamReaderWriter_Bijection(t *testing.T) {
+	t.Parallel()
+
+	for name, mode := range map[string]Mode{"CTR": CTR, "OFB": OFB, "CFB": CFB} {
+		mode := mode
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			plaintext1 := make([]byte, 123)
+			_, err := io.ReadFull(rand.Reader, plaintext1)
+			assert.Nil(t, err)
+
+			key, err := crypto.RandomAES256Key()
+			assert.Nil(t, err)
+
+			sr, err := NewStreamReader(key, bytes.NewBuffer(plaintext1), mode)
+			assert.Nil(t, err)
+
+			var wBuf bytes.Buffer
+			sw := NewStreamWriter(key, &wBuf, mode)
+
+			_, err = io.Copy(sw, sr)
+			assert.Nil(t, err)
+
+			assert.Equal(t, plaintext1, wBuf.Bytes())
+		})
+	}
+}
+
+func TestCFBStreamMatchesStdlib(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("example key 1234")
+	plaintext := []byte("some plaintext")
+
+	block, err := aes.NewCipher(key)
+	assert.Nil(t, err)
+
+	iv := make([]byte, 16)
+	_, err = io.ReadFull(rand.Reader, iv)
+	assert.Nil(t, err)
+
+	want := make([]byte, len(plaintext))
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(want, plaintext)
+
+	sr, err := newStreamReaderWithVector(key, ioutil.NopCloser(bytes.NewBuffer(plaintext)), iv, CFB)
+	assert.Nil(t, err)
+
+	got := make([]byte, len(iv)+len(plaintext))
+	_, err = io.ReadFull(sr, got)
+	assert.Nil(t, err)
+
+	assert.Equal(t, iv, got[:len(iv)])
+	assert.Equal(t, want, got[len(iv):])
+}
@@ -0,0 +1,21 @@
+// Package crypto provides small, dependency-free helpers around the
+// standard library's crypto primitives.
+package crypto
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+// AES256KeySize is the key size in bytes required for AES-256.
+const AES256KeySize = 32
+
+// RandomAES256Key returns 32 bytes read from crypto/rand, suitable for use
+// as an AES-256 key.
+func RandomAES256Key() ([]byte, error) {
+	key := make([]byte, AES256KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}